@@ -112,7 +112,7 @@ func main() {
 
 	setMaxProcs()
 
-	memoryStorage, err := NewMemoryStorage()
+	memoryStorage, backendStorage, err := NewMemoryStorage()
 	if err != nil {
 		glog.Fatalf("Failed to initialize storage driver: %s", err)
 	}
@@ -150,6 +150,12 @@ func main() {
 	if err := containerManager.Start(); err != nil {
 		glog.Fatalf("Failed to start container manager: %v", err)
 	}
+	if err := ForwardMachineInfo(backendStorage, containerManager); err != nil {
+		glog.Warningf("Failed to hand the machine info to the storage driver: %v", err)
+	}
+	if err := ForwardStorageEvents(backendStorage, containerManager); err != nil {
+		glog.Warningf("Failed to watch events for the storage driver: %v", err)
+	}
 
 	// Install signal handler.
 	installSignalHandler(containerManager)
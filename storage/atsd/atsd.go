@@ -15,6 +15,7 @@
 package atsd
 
 import (
+	"context"
 	"flag"
 	"io/ioutil"
 	"net/url"
@@ -26,6 +27,7 @@ import (
 	info "github.com/google/cadvisor/info/v1"
 	"github.com/google/cadvisor/manager"
 	"github.com/google/cadvisor/storage"
+	"github.com/google/cadvisor/version"
 
 	atsdStorageDriver "github.com/axibase/atsd-storage-driver/storage"
 )
@@ -33,6 +35,7 @@ import (
 const (
 	startDelay           = 15 * time.Second       // waiting to store enough data for all entities before send
 	timestampPeriodError = 250 * time.Millisecond // time error accumulated during housekeeping
+	stopTimeout          = 10 * time.Second       // maximum time to wait for the queued data to be sent on close
 
 	metricPrefix = "cadvisor"
 
@@ -43,6 +46,7 @@ const (
 	taskGroup      = "task"
 	networkGroup   = "network"
 	filesytemGroup = "filesystem"
+	machineGroup   = "machine"
 
 	dockerHostDefault = "empty_flag"
 )
@@ -50,16 +54,27 @@ const (
 var (
 	protocol             = flag.String("storage_driver_atsd_protocol", "tcp", "transfer protocol. Supported protocols: http, https, udp, tcp")
 	skipVerify           = flag.Bool("storage_driver_atsd_skip_verify", false, "controls whether a client verifies the server's certificate chain and host name")
+	caFile               = flag.String("storage_driver_atsd_ca_file", "", "PEM file with CA certificates trusted in addition to the system ones when connecting via https")
+	clientCertFile       = flag.String("storage_driver_atsd_client_cert", "", "PEM file with the client certificate for mutual TLS, requires storage_driver_atsd_client_key")
+	clientKeyFile        = flag.String("storage_driver_atsd_client_key", "", "PEM file with the private key of the client certificate")
+	proxy                = flag.String("storage_driver_atsd_proxy", "", "URL of the proxy to reach ATSD via http or https. Defaults to HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables")
+	userAgent            = flag.String("storage_driver_atsd_user_agent", "", "User-Agent of http and https requests to ATSD. Defaults to cAdvisor/<version>")
 	senderGoroutineLimit = flag.Int("storage_driver_atsd_sender_thread_limit", 4, "maximum thread (goroutine) count sending data to ATSD server via tcp/udp")
 	memstoreLimit        = flag.Uint("storage_driver_atsd_buffer_limit", 1000000, "maximum network command count stored in buffer before being sent into ATSD")
 
 	dockerHost             = flag.String("storage_driver_atsd_docker_host", dockerHostDefault, "hostname of the docker host, used as entity prefix")
+	hostEntity             = flag.String("storage_driver_atsd_host_entity", "", "entity of the machine-level metrics (cores, memory and filesystem capacity). Defaults to storage_driver_atsd_docker_host, or the hostname if it is empty")
 	includeAllMajorNumbers = flag.Bool("storage_driver_atsd_store_major_numbers", false, "include statistics for devices with all available major numbers")
 	userCgroupsEnabled     = flag.Bool("storage_driver_atsd_store_user_cgroups", false, "include statistics for \"user\" cgroups (for example: docker-host/user.*)")
 	propertyInterval       = flag.Duration("storage_driver_atsd_property_interval", 1*time.Minute, "container property (host, id, namespace) update interval. Should be >= housekeeping_interval")
 	samplingInterval       = flag.Duration("storage_driver_atsd_sampling_interval", *manager.HousekeepingInterval, "series sampling interval. Should be >= housekeeping_interval")
+	lifecycleMessages      = flag.Bool("storage_driver_atsd_lifecycle_messages", false, "send container creation, deletion and OOM events as ATSD messages")
+	lifecycleMessageLimit  = flag.Int("storage_driver_atsd_lifecycle_message_limit", 100, "maximum count of container lifecycle messages per minute, the excess is dropped. 0 means no limit")
+	labelPrefix            = flag.String("storage_driver_atsd_label_prefix", "", "prefix of the container entity and property tags copied from container labels, for example \"label.\" to keep labels from shadowing tags like container_id")
+	labels                 = flag.String("storage_driver_atsd_labels", "", "comma-separated container labels copied as tags. Empty copies all labels")
 
 	deduplication = make(deduplicationParamsList)
+	headers       = make(headerList)
 )
 
 func init() {
@@ -67,9 +82,11 @@ func init() {
 
 	flag.Var(&deduplication, "storage_driver_atsd_deduplication",
 		"Specify optional deduplication settings for a metric group using 'group:interval:threshold' syntax. "+
-			"Group - Metric group to which the setting applies. Supported metric groups in cAdvisor: cpu, memory, io, network, task, filesystem. "+
+			"Group - Metric group to which the setting applies. Supported metric groups in cAdvisor: cpu, memory, io, network, task, filesystem, machine. "+
 			"Interval - Maximum delay between the current and previously sent samples. If exceeded, the current sample is sent to ATSD regardless of the specified threshold. "+
 			"Threshold - Absolute or percentage difference between the current and previously sent sample values. If the absolute difference is within the threshold and elapsed time is within Interval, the value is discarded.")
+	flag.Var(&headers, "storage_driver_atsd_header",
+		"Header added to http and https requests to ATSD using 'name: value' syntax, for example a routing header required by a gateway. Can be repeated.")
 	if *dockerHost == dockerHostDefault {
 		content, err := ioutil.ReadFile("/rootfs/etc/hostname")
 		if err != nil {
@@ -99,6 +116,12 @@ func newStorage() (storage.StorageDriver, error) {
 		SamplingInterval:       *samplingInterval,
 		IncludeAllMajorNumbers: *includeAllMajorNumbers,
 		UserCgroupsEnabled:     *userCgroupsEnabled,
+		Labels:                 LabelOptions{Prefix: *labelPrefix},
+	}
+	for _, label := range strings.Split(*labels, ",") {
+		if label = strings.TrimSpace(label); label != "" {
+			cadvisorConfig.Labels.Include = append(cadvisorConfig.Labels.Include, label)
+		}
 	}
 
 	innerStorageConfig := atsdStorageDriver.GetDefaultConfig()
@@ -106,8 +129,25 @@ func newStorage() (storage.StorageDriver, error) {
 	innerStorageConfig.SenderGoroutineLimit = *senderGoroutineLimit
 	innerStorageConfig.GroupParams = deduplication
 	innerStorageConfig.InsecureSkipVerify = *skipVerify
+	innerStorageConfig.TLS = atsdStorageDriver.TLSOptions{
+		CAFile:   *caFile,
+		CertFile: *clientCertFile,
+		KeyFile:  *clientKeyFile,
+	}
 	innerStorageConfig.MetricPrefix = metricPrefix
 	innerStorageConfig.UpdateInterval = *storage.ArgDbBufferDuration
+	if *proxy != "" {
+		proxyUrl, err := url.Parse(*proxy)
+		if err != nil {
+			return nil, err
+		}
+		innerStorageConfig.ProxyUrl = proxyUrl
+	}
+	innerStorageConfig.UserAgent = *userAgent
+	if innerStorageConfig.UserAgent == "" {
+		innerStorageConfig.UserAgent = "cAdvisor/" + version.Info["version"]
+	}
+	innerStorageConfig.Headers = headers
 	innerStorageConfig.Url = &url.URL{
 		Scheme: *protocol,
 		User:   url.UserPassword(*storage.ArgDbUsername, *storage.ArgDbPassword),
@@ -119,6 +159,13 @@ func newStorage() (storage.StorageDriver, error) {
 		return nil, err
 	}
 	innerStorageConfig.SelfMetricEntity = cadvisorConfig.DockerHost + "/" + hostname
+	cadvisorConfig.HostEntity = *hostEntity
+	if cadvisorConfig.HostEntity == "" {
+		cadvisorConfig.HostEntity = cadvisorConfig.DockerHost
+	}
+	if cadvisorConfig.HostEntity == "" {
+		cadvisorConfig.HostEntity = hostname
+	}
 
 	storageFactory := atsdStorageDriver.NewFactoryFromConfig(innerStorageConfig)
 	innerStorage, err := storageFactory.Create()
@@ -132,6 +179,11 @@ func newStorage() (storage.StorageDriver, error) {
 		lastTimePropertyMapMutex:   &sync.Mutex{},
 		lastTimeSentSeriesMap:      make(map[string]time.Time),
 		lastTimeSentSeriesMapMutex: &sync.Mutex{},
+		unitCache:                  newUnitCache(unitCacheSize, unitCacheTTL),
+		hostname:                   hostname,
+	}
+	if *lifecycleMessages {
+		storageDriver.lifecycle = newLifecycleRecorder(cadvisorConfig.DockerHost, *lifecycleMessageLimit)
 	}
 
 	time.AfterFunc(startDelay, func() {
@@ -151,6 +203,14 @@ type Storage struct {
 	lastTimePropertyMapMutex   *sync.Mutex
 	lastTimeSentSeriesMap      map[string]time.Time
 	lastTimeSentSeriesMapMutex *sync.Mutex
+	// units of the metrics already described for each entity
+	unitCache *unitCache
+	// nil unless container lifecycle messages are enabled
+	lifecycle *lifecycleRecorder
+
+	hostname     string
+	machineInfo  *info.MachineInfo
+	machineMutex sync.Mutex
 }
 
 func (self *Storage) AddStats(ref info.ContainerReference, stats *info.ContainerStats) error {
@@ -171,15 +231,23 @@ func (self *Storage) AddStats(ref info.ContainerReference, stats *info.Container
 			self.innerStorage.QueuedSendSeriesCommands(taskGroup, taskSeriesCommands)
 			self.innerStorage.QueuedSendSeriesCommands(networkGroup, networkSeriesCommands)
 			self.innerStorage.QueuedSendSeriesCommands(filesytemGroup, fileSystemSeriesCommands)
+			units := self.unitCache.UnitPropertyCommands(self.DockerHost+ref.Name, stats.Timestamp,
+				cpuSeriesCommands, derivedCpuSeries, ioSeriesCommands, memorySeriesCommands, taskSeriesCommands, networkSeriesCommands, fileSystemSeriesCommands)
+			if len(units) > 0 {
+				self.innerStorage.QueuedSendPropertyCommands(units)
+			}
+			if ref.Name == "/" {
+				self.sendMachineSeries(stats.Timestamp)
+			}
 			self.lastTimeSentSeriesMapMutex.Lock()
 			self.lastTimeSentSeriesMap[ref.Name] = stats.Timestamp
 			self.lastTimeSentSeriesMapMutex.Unlock()
 		}
 
 		if self.needToSendProperties(ref.Name, stats.Timestamp) {
-			properties := RefToPropertyCommands(self.DockerHost, ref, stats.Timestamp)
+			properties := RefToPropertyCommands(self.DockerHost, ref, self.Labels, stats.Timestamp)
 			self.innerStorage.QueuedSendPropertyCommands(properties)
-			entities := RefToEntityCommands(self.DockerHost, ref)
+			entities := RefToEntityCommands(self.DockerHost, ref, self.Labels)
 			self.innerStorage.QueuedSendEntityTagCommands(entities)
 
 			self.lastTimePropertyMapMutex.Lock()
@@ -190,10 +258,52 @@ func (self *Storage) AddStats(ref info.ContainerReference, stats *info.Container
 	return nil
 }
 
+// AddMachineInfo tags the host entity with the machine info, its capacities are sent as host series
+// along with the series of the root container
+func (self *Storage) AddMachineInfo(machineInfo *info.MachineInfo) error {
+	self.machineMutex.Lock()
+	self.machineInfo = machineInfo
+	self.machineMutex.Unlock()
+	self.innerStorage.QueuedSendEntityTagCommands(MachineEntityTagCommands(self.HostEntity, self.hostname, machineInfo))
+	return nil
+}
+
+func (self *Storage) sendMachineSeries(timestamp time.Time) {
+	self.machineMutex.Lock()
+	machineInfo := self.machineInfo
+	self.machineMutex.Unlock()
+	if machineInfo == nil {
+		return
+	}
+	machineSeriesCommands := MachineSeriesCommands(self.HostEntity, machineInfo, timestamp)
+	self.innerStorage.QueuedSendSeriesCommands(machineGroup, machineSeriesCommands)
+	if units := self.unitCache.UnitPropertyCommands(self.HostEntity, timestamp, machineSeriesCommands); len(units) > 0 {
+		self.innerStorage.QueuedSendPropertyCommands(units)
+	}
+}
+
+// RecordsEvents reports whether lifecycle messages are enabled
+func (self *Storage) RecordsEvents() bool {
+	return self.lifecycle != nil
+}
+
+// AddEvent sends the container lifecycle event as a message if lifecycle messages are enabled
+func (self *Storage) AddEvent(ref info.ContainerReference, spec info.ContainerSpec, event *info.Event) error {
+	if self.lifecycle == nil || !isEnabledToStore(info.ContainerReference{Name: event.ContainerName}, self.UserCgroupsEnabled) {
+		return nil
+	}
+	if messages := self.lifecycle.MessageCommands(ref, spec, event); len(messages) > 0 {
+		self.innerStorage.QueuedSendMessageCommands(messages)
+	}
+	return nil
+}
+
 func (self *Storage) Close() error {
 	self.innerStorage.StopPeriodicSending()
 	self.innerStorage.ForceSend()
-	return nil
+	ctx, cancel := context.WithTimeout(context.Background(), stopTimeout)
+	defer cancel()
+	return self.innerStorage.StopSending(ctx)
 }
 
 func (self *Storage) needToSendProperties(containerRefName string, timestamp time.Time) bool {
@@ -21,6 +21,7 @@ import (
 	"strings"
 	"time"
 
+	atsdHttp "github.com/axibase/atsd-api-go/http"
 	atsdStorageDriver "github.com/axibase/atsd-storage-driver/storage"
 )
 
@@ -60,10 +61,35 @@ func (self deduplicationParamsList) Set(value string) error {
 	return nil
 }
 
+type headerList map[string]string
+
+func (self headerList) String() string {
+	return fmt.Sprint(map[string]string(self))
+}
+
+func (self headerList) Set(value string) error {
+	parts := strings.SplitN(value, ":", 2)
+	if len(parts) != 2 {
+		return errors.New("Unable to parse a header. Expected format: \"name: value\"")
+	}
+	header := map[string]string{strings.TrimSpace(parts[0]): strings.TrimSpace(parts[1])}
+	if err := atsdHttp.ValidateHeaders(header); err != nil {
+		return err
+	}
+	for name, value := range header {
+		self[name] = value
+	}
+	return nil
+}
+
 type cadvisorParams struct {
 	IncludeAllMajorNumbers bool
 	UserCgroupsEnabled     bool
 	PropertyInterval       time.Duration
 	SamplingInterval       time.Duration
 	DockerHost             string
+	// entity of the machine-level metrics
+	HostEntity string
+	// container labels copied as tags
+	Labels LabelOptions
 }
@@ -116,6 +116,26 @@ const (
 	containerHostTag        = "container_host"
 )
 
+// LabelOptions selects the container labels copied as tags of the container entity and property
+type LabelOptions struct {
+	// prepended to the tag keys of the labels so they do not shadow the tags set by cAdvisor, for example "label."
+	Prefix string
+	// names of the labels to copy, empty copies all labels
+	Include []string
+}
+
+func (self LabelOptions) includes(label string) bool {
+	if len(self.Include) == 0 {
+		return true
+	}
+	for _, name := range self.Include {
+		if name == label {
+			return true
+		}
+	}
+	return false
+}
+
 // Tags
 const (
 	device        = "device"
@@ -275,7 +295,7 @@ func setSeriesTimestamp(seriesCommands []*atsdNet.SeriesCommand, timestamp time.
 	}
 }
 
-func extractTagsFromRef(machineName string, ref info.ContainerReference) map[string]string {
+func extractTagsFromRef(machineName string, ref info.ContainerReference, labels LabelOptions) map[string]string {
 	tags := map[string]string{}
 
 	if ref.Namespace == "" {
@@ -309,18 +329,20 @@ func extractTagsFromRef(machineName string, ref info.ContainerReference) map[str
 	}
 
 	for key, val := range ref.Labels {
-		tags[key] = val
+		if labels.includes(key) {
+			tags[labels.Prefix+key] = val
+		}
 	}
 
 	return tags
 }
 
-func RefToPropertyCommands(machineName string, ref info.ContainerReference, timestamp time.Time) []*atsdNet.PropertyCommand {
+func RefToPropertyCommands(machineName string, ref info.ContainerReference, labels LabelOptions, timestamp time.Time) []*atsdNet.PropertyCommand {
 	entity := machineName + ref.Name
 
 	var propertyCommand *atsdNet.PropertyCommand
 
-	tags := extractTagsFromRef(machineName, ref)
+	tags := extractTagsFromRef(machineName, ref, labels)
 	for name, val := range tags {
 		if propertyCommand == nil {
 			propertyCommand = atsdNet.NewPropertyCommand(propertyType, entity, name, val)
@@ -335,8 +357,8 @@ func RefToPropertyCommands(machineName string, ref info.ContainerReference, time
 		return []*atsdNet.PropertyCommand{}
 	}
 }
-func RefToEntityCommands(machineName string, ref info.ContainerReference) []*atsdNet.EntityTagCommand {
-	tags := extractTagsFromRef(machineName, ref)
+func RefToEntityCommands(machineName string, ref info.ContainerReference, labels LabelOptions) []*atsdNet.EntityTagCommand {
+	tags := extractTagsFromRef(machineName, ref, labels)
 	var entity *atsdNet.EntityTagCommand
 	for key, val := range tags {
 		if entity == nil {
@@ -110,12 +110,12 @@ func TestRefToPropertyAndEntityTags(t *testing.T) {
 		assertProps := tests[i].props
 		assertEntityTagCommands := tests[i].entityTagCommands
 
-		props := RefToPropertyCommands(machineName, *ref, time.Unix(0, int64(timestamp)*time.Millisecond.Nanoseconds()))
+		props := RefToPropertyCommands(machineName, *ref, LabelOptions{}, time.Unix(0, int64(timestamp)*time.Millisecond.Nanoseconds()))
 		if !IsPropertiesEqual(props, assertProps) {
 			t.Error("IsPropertiesEqual = false result props: ", props, "assert props: ", assertProps)
 		}
 
-		entityTagCommands := RefToEntityCommands(machineName, *ref)
+		entityTagCommands := RefToEntityCommands(machineName, *ref, LabelOptions{})
 		if !IsEntityTagCommandEquals(entityTagCommands, assertEntityTagCommands) {
 			t.Error("IsEntityTagCommandEquals = false result entityTagCommands: ", entityTagCommands, "assert entityTagCommands: ", assertEntityTagCommands)
 		}
@@ -123,6 +123,37 @@ func TestRefToPropertyAndEntityTags(t *testing.T) {
 	}
 }
 
+func TestLabelTags(t *testing.T) {
+	ref := info.ContainerReference{
+		Name:   "/docker/abc",
+		Id:     "abc",
+		Labels: map[string]string{"container_id": "user-value", "app": "web", "team": "ops"},
+	}
+	for _, test := range []struct {
+		name     string
+		labels   LabelOptions
+		expected map[string]string
+	}{
+		{"all labels, no prefix", LabelOptions{}, map[string]string{"container_id": "user-value", "app": "web", "team": "ops"}},
+		{"prefix", LabelOptions{Prefix: "label."}, map[string]string{"container_id": "abc", "label.container_id": "user-value", "label.app": "web", "label.team": "ops"}},
+		{"allow-list", LabelOptions{Include: []string{"app"}}, map[string]string{"container_id": "abc", "app": "web"}},
+		{"prefixed allow-list", LabelOptions{Prefix: "label.", Include: []string{"app", "missing"}}, map[string]string{"container_id": "abc", "label.app": "web"}},
+	} {
+		expected := map[string]string{containerNamespaceTag: "docker"}
+		for key, value := range test.expected {
+			expected[key] = value
+		}
+		tags := RefToEntityCommands("host", ref, test.labels)[0].Tags()
+		if !reflect.DeepEqual(tags, expected) {
+			t.Errorf("%v: entity tags = %v, expected %v", test.name, tags, expected)
+		}
+		propertyTags := RefToPropertyCommands("host", ref, test.labels, time.Unix(1, 0))[0].Tags()
+		if !reflect.DeepEqual(propertyTags, expected) {
+			t.Errorf("%v: property tags = %v, expected %v", test.name, propertyTags, expected)
+		}
+	}
+}
+
 func IsPropertiesEqual(props1, props2 []*atsdNet.PropertyCommand) bool {
 	return (len(props1) == 0 && len(props2) == 0) || reflect.DeepEqual(props1, props2)
 }
@@ -0,0 +1,146 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package atsd
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	info "github.com/google/cadvisor/info/v1"
+
+	atsdNet "github.com/axibase/atsd-api-go/net"
+	"github.com/golang/glog"
+)
+
+const (
+	lifecycleMessageType   = "container"
+	lifecycleMessageSource = "cadvisor"
+	// window the lifecycle message limit applies to
+	lifecycleLimitWindow = 1 * time.Minute
+
+	containerImageTag = "image"
+	lifecycleEventTag = "event"
+	oomKillPidTag     = "pid"
+	oomKillProcessTag = "process"
+)
+
+// lifecycleEventMessages holds the message text and the severity of each container event type
+var lifecycleEventMessages = map[info.EventType]struct{ message, severity string }{
+	info.EventContainerCreation: {"container created", "NORMAL"},
+	info.EventContainerDeletion: {"container deleted", "NORMAL"},
+	info.EventOom:               {"container out of memory", "WARNING"},
+	info.EventOomKill:           {"container process killed by the OOM killer", "MAJOR"},
+}
+
+// lifecycleRecorder turns container events into message commands. It remembers the reference and the image
+// of the created containers to tag the events of deleted ones, and sends at most limit messages
+// per lifecycleLimitWindow of event time, 0 means no limit
+type lifecycleRecorder struct {
+	machineName string
+	limit       int
+
+	mutex       sync.Mutex
+	containers  map[string]lifecycleContainer
+	windowStart time.Time
+	sent        int
+	dropped     int
+}
+
+type lifecycleContainer struct {
+	ref   info.ContainerReference
+	image string
+}
+
+func newLifecycleRecorder(machineName string, limit int) *lifecycleRecorder {
+	return &lifecycleRecorder{
+		machineName: machineName,
+		limit:       limit,
+		containers:  map[string]lifecycleContainer{},
+	}
+}
+
+// MessageCommands returns the message of the event, none if its type is not recorded or the limit is reached.
+// ref and spec describe the container, they are empty if it is already gone
+func (self *lifecycleRecorder) MessageCommands(ref info.ContainerReference, spec info.ContainerSpec, event *info.Event) []*atsdNet.MessageCommand {
+	description, ok := lifecycleEventMessages[event.EventType]
+	if !ok {
+		return nil
+	}
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	container := self.container(ref, spec, event)
+	if !self.allow(event.Timestamp) {
+		return nil
+	}
+
+	entity := self.machineName + event.ContainerName
+	command := atsdNet.NewMessageCommand(entity, description.message).
+		SetTag("type", lifecycleMessageType).
+		SetTag("source", lifecycleMessageSource).
+		SetTag("severity", description.severity).
+		SetTag(lifecycleEventTag, string(event.EventType)).
+		SetTimestamp(atsdNet.Millis(event.Timestamp.UnixNano() / time.Millisecond.Nanoseconds()))
+	if container.ref.Id != "" {
+		command.SetTag(containerIdTag, container.ref.Id)
+	}
+	if container.image != "" {
+		command.SetTag(containerImageTag, container.image)
+	}
+	if oomKill := event.EventData.OomKill; oomKill != nil {
+		command.SetTag(oomKillPidTag, strconv.Itoa(oomKill.Pid))
+		command.SetTag(oomKillProcessTag, oomKill.ProcessName)
+	}
+	return []*atsdNet.MessageCommand{command}
+}
+
+// container returns what is known about the container of the event and forgets deleted containers
+func (self *lifecycleRecorder) container(ref info.ContainerReference, spec info.ContainerSpec, event *info.Event) lifecycleContainer {
+	container, known := self.containers[event.ContainerName]
+	if ref.Name != "" {
+		container.ref = ref
+	}
+	if spec.Image != "" {
+		container.image = spec.Image
+	}
+	switch {
+	case event.EventType == info.EventContainerDeletion:
+		delete(self.containers, event.ContainerName)
+	case known || event.EventType == info.EventContainerCreation:
+		self.containers[event.ContainerName] = container
+	}
+	return container
+}
+
+// allow counts the message into the window of timestamp and reports whether it is within the limit
+func (self *lifecycleRecorder) allow(timestamp time.Time) bool {
+	if self.limit <= 0 {
+		return true
+	}
+	if timestamp.Sub(self.windowStart) >= lifecycleLimitWindow || timestamp.Before(self.windowStart) {
+		if self.dropped > 0 {
+			glog.Warningf("Dropped %v container lifecycle messages exceeding the limit of %v per %v", self.dropped, self.limit, lifecycleLimitWindow)
+		}
+		self.windowStart = timestamp
+		self.sent = 0
+		self.dropped = 0
+	}
+	if self.sent >= self.limit {
+		self.dropped++
+		return false
+	}
+	self.sent++
+	return true
+}
@@ -0,0 +1,101 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package atsd
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	info "github.com/google/cadvisor/info/v1"
+)
+
+func TestLifecycleEventsBecomeMessages(t *testing.T) {
+	recorder := newLifecycleRecorder("hostname", 0)
+	name := "/docker/abc"
+	ref := info.ContainerReference{Name: name, Id: "abc", Aliases: []string{"web", "abc"}}
+	spec := info.ContainerSpec{Image: "nginx:1.11"}
+	timestamp := time.Unix(123456, 0)
+	events := []struct {
+		ref   info.ContainerReference
+		spec  info.ContainerSpec
+		event *info.Event
+	}{
+		{ref, spec, &info.Event{ContainerName: name, Timestamp: timestamp, EventType: info.EventContainerCreation}},
+		{ref, spec, &info.Event{ContainerName: name, Timestamp: timestamp, EventType: info.EventOomKill,
+			EventData: info.EventData{OomKill: &info.OomKillEventData{Pid: 42, ProcessName: "nginx"}}}},
+		// the deleted container is gone, its reference and image are remembered from the creation
+		{info.ContainerReference{}, info.ContainerSpec{}, &info.Event{ContainerName: name, Timestamp: timestamp, EventType: info.EventContainerDeletion}},
+	}
+	expected := []struct {
+		message string
+		tags    map[string]string
+	}{
+		{"container created", map[string]string{"type": "container", "source": "cadvisor", "severity": "NORMAL",
+			"event": "containerCreation", "container_id": "abc", "image": "nginx:1.11"}},
+		{"container process killed by the OOM killer", map[string]string{"type": "container", "source": "cadvisor", "severity": "MAJOR",
+			"event": "oomKill", "container_id": "abc", "image": "nginx:1.11", "pid": "42", "process": "nginx"}},
+		{"container deleted", map[string]string{"type": "container", "source": "cadvisor", "severity": "NORMAL",
+			"event": "containerDeletion", "container_id": "abc", "image": "nginx:1.11"}},
+	}
+	for i, event := range events {
+		messages := recorder.MessageCommands(event.ref, event.spec, event.event)
+		if len(messages) != 1 {
+			t.Fatalf("%v: %v messages, expected 1", event.event.EventType, len(messages))
+		}
+		message := messages[0]
+		if message.Entity() != "hostname"+name || message.Message() != expected[i].message {
+			t.Errorf("%v: message %q of %v, expected %q of hostname%v", event.event.EventType, message.Message(), message.Entity(), expected[i].message, name)
+		}
+		if !reflect.DeepEqual(message.Tags(), expected[i].tags) {
+			t.Errorf("%v: tags = %v, expected %v", event.event.EventType, message.Tags(), expected[i].tags)
+		}
+		if timestamp := message.Timestamp(); timestamp == nil || *timestamp != 123456000 {
+			t.Errorf("%v: timestamp = %v, expected 123456000", event.event.EventType, timestamp)
+		}
+	}
+	if len(recorder.containers) != 0 {
+		t.Errorf("remembered %v containers after the deletion, expected none", len(recorder.containers))
+	}
+}
+
+func TestLifecycleMessagesAreRateLimited(t *testing.T) {
+	recorder := newLifecycleRecorder("hostname", 2)
+	start := time.Unix(123456, 0)
+	sent := 0
+	// a mass restart within a second
+	for i := 0; i < 10; i++ {
+		event := &info.Event{ContainerName: "/docker/container", Timestamp: start.Add(time.Duration(i) * time.Millisecond), EventType: info.EventContainerCreation}
+		sent += len(recorder.MessageCommands(info.ContainerReference{}, info.ContainerSpec{}, event))
+	}
+	if sent != 2 {
+		t.Errorf("sent %v messages, expected the limit of 2", sent)
+	}
+	event := &info.Event{ContainerName: "/docker/container", Timestamp: start.Add(lifecycleLimitWindow), EventType: info.EventContainerDeletion}
+	if messages := recorder.MessageCommands(info.ContainerReference{}, info.ContainerSpec{}, event); len(messages) != 1 {
+		t.Errorf("sent %v messages in the next window, expected 1", len(messages))
+	}
+}
+
+func TestEventsAreRecordedOnlyWithLifecycleMessages(t *testing.T) {
+	storage := &Storage{}
+	if storage.RecordsEvents() {
+		t.Error("records events with lifecycle messages disabled, expected the event watch to be skipped")
+	}
+	storage.lifecycle = newLifecycleRecorder("hostname", 0)
+	if !storage.RecordsEvents() {
+		t.Error("does not record events with lifecycle messages enabled")
+	}
+}
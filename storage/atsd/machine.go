@@ -0,0 +1,75 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package atsd
+
+import (
+	"strconv"
+	"time"
+
+	info "github.com/google/cadvisor/info/v1"
+
+	atsdNet "github.com/axibase/atsd-api-go/net"
+)
+
+// metrics of the machine cAdvisor runs on, the host prefix keeps them apart from the container metrics
+const (
+	hostCpuCores              = "cadvisor.host.cpu.cores"
+	hostCpuFrequency          = "cadvisor.host.cpu.frequency"
+	hostMemoryCapacity        = "cadvisor.host.memory.capacity"
+	hostFilesystemCapacity    = "cadvisor.host.filesystem.capacity"
+	hostFilesystemInodesTotal = "cadvisor.host.filesystem.inodes"
+)
+
+// tags of the host entity
+const (
+	hostnameTag       = "hostname"
+	hostCpuCountTag   = "cpu_count"
+	hostMemoryTag     = "memory_total"
+	hostMachineIdTag  = "machine_id"
+	hostSystemUuidTag = "system_uuid"
+)
+
+func MachineSeriesCommands(entity string, machineInfo *info.MachineInfo, timestamp time.Time) []*atsdNet.SeriesCommand {
+	seriesCommands := []*atsdNet.SeriesCommand{
+		atsdNet.NewSeriesCommand(entity, hostCpuCores, atsdNet.Int64(machineInfo.NumCores)).
+			SetMetricValue(hostCpuFrequency, atsdNet.Uint64(machineInfo.CpuFrequency)).
+			SetMetricValue(hostMemoryCapacity, atsdNet.Uint64(machineInfo.MemoryCapacity)),
+	}
+	for _, fsInfo := range machineInfo.Filesystems {
+		seriesCommands = append(seriesCommands, atsdNet.NewSeriesCommand(entity, hostFilesystemCapacity, atsdNet.Uint64(fsInfo.Capacity)).
+			SetMetricValue(hostFilesystemInodesTotal, atsdNet.Uint64(fsInfo.Inodes)).
+			SetTag(device, fsInfo.Device).
+			SetTag(fsType, fsInfo.Type))
+	}
+
+	setSeriesTimestamp(seriesCommands, timestamp)
+
+	return seriesCommands
+}
+
+func MachineEntityTagCommands(entity, hostname string, machineInfo *info.MachineInfo) []*atsdNet.EntityTagCommand {
+	command := atsdNet.NewEntityTagCommand(entity, hostCpuCountTag, strconv.Itoa(machineInfo.NumCores)).
+		SetTag(hostMemoryTag, strconv.FormatUint(machineInfo.MemoryCapacity, 10))
+	if hostname != "" {
+		command.SetTag(hostnameTag, hostname)
+	}
+	if machineInfo.MachineID != "" {
+		command.SetTag(hostMachineIdTag, machineInfo.MachineID)
+	}
+	if machineInfo.SystemUUID != "" {
+		command.SetTag(hostSystemUuidTag, machineInfo.SystemUUID)
+	}
+	return []*atsdNet.EntityTagCommand{command}
+}
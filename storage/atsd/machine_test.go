@@ -0,0 +1,70 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package atsd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	atsdNet "github.com/axibase/atsd-api-go/net"
+	info "github.com/google/cadvisor/info/v1"
+)
+
+func TestMachineInfoRoutesToHostEntity(t *testing.T) {
+	machineInfo := &info.MachineInfo{
+		NumCores:       8,
+		CpuFrequency:   2400000,
+		MemoryCapacity: 16 << 30,
+		MachineID:      "machine-1",
+		Filesystems:    []info.FsInfo{{Device: "/dev/sda1", Type: "vfs", Capacity: 100 << 30, Inodes: 6553600}},
+	}
+	timestamp := time.Unix(123456, 0)
+
+	expectedSeries := []*atsdNet.SeriesCommand{
+		atsdNet.NewSeriesCommand("host", hostCpuCores, atsdNet.Int64(8)).
+			SetMetricValue(hostCpuFrequency, atsdNet.Uint64(2400000)).
+			SetMetricValue(hostMemoryCapacity, atsdNet.Uint64(16<<30)).
+			SetTimestamp(123456000),
+		atsdNet.NewSeriesCommand("host", hostFilesystemCapacity, atsdNet.Uint64(100<<30)).
+			SetMetricValue(hostFilesystemInodesTotal, atsdNet.Uint64(6553600)).
+			SetTag(device, "/dev/sda1").
+			SetTag(fsType, "vfs").
+			SetTimestamp(123456000),
+	}
+	if series := MachineSeriesCommands("host", machineInfo, timestamp); !reflect.DeepEqual(series, expectedSeries) {
+		t.Errorf("host series = %v, expected %v", series, expectedSeries)
+	}
+	expectedTags := map[string]string{"hostname": "node-1", "cpu_count": "8", "memory_total": "17179869184", "machine_id": "machine-1"}
+	entities := MachineEntityTagCommands("host", "node-1", machineInfo)
+	if len(entities) != 1 || entities[0].Entity() != "host" || !reflect.DeepEqual(entities[0].Tags(), expectedTags) {
+		t.Errorf("host entity tags = %v, expected %v of host", entities, expectedTags)
+	}
+}
+
+func TestHostMetricsDoNotCollideWithContainerMetrics(t *testing.T) {
+	hostMetrics := map[string]bool{hostCpuCores: true, hostCpuFrequency: true, hostMemoryCapacity: true, hostFilesystemCapacity: true, hostFilesystemInodesTotal: true}
+	for metric := range hostMetrics {
+		if !strings.HasPrefix(metric, metricPrefix+".host.") {
+			t.Errorf("host metric %v lacks the host prefix", metric)
+		}
+	}
+	for metric := range metricUnits {
+		if !hostMetrics[metric] && strings.HasPrefix(metric, metricPrefix+".host.") {
+			t.Errorf("container metric %v uses the host prefix", metric)
+		}
+	}
+}
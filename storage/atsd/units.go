@@ -0,0 +1,232 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package atsd
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+	"time"
+
+	atsdNet "github.com/axibase/atsd-api-go/net"
+)
+
+// property type describing the units of the metrics of an entity, tags are metric names and values their units
+const unitsPropertyType = "cadvisor.units"
+
+const (
+	// entities the units are remembered for, the least recently reported ones are forgotten first
+	unitCacheSize = 10000
+	// interval after which the units of an entity are described again
+	unitCacheTTL = 24 * time.Hour
+)
+
+const (
+	unitBytes        = "bytes"
+	unitNanoseconds  = "nanoseconds"
+	unitMilliseconds = "milliseconds"
+	unitCount        = "count"
+	unitPercent      = "percent"
+	unitKilohertz    = "kilohertz"
+)
+
+var metricUnits = map[string]string{
+	containerCpuUsageUser:   unitNanoseconds,
+	containerCpuUsageTotal:  unitNanoseconds,
+	containerCpuUsageSystem: unitNanoseconds,
+	containerCpuLoadAverage: unitCount,
+	containerCpuUsagePerCpu: unitNanoseconds,
+
+	containerCpuUsageSystemPct:     unitPercent,
+	containerCpuUsageTotalPct:      unitPercent,
+	containerCpuUsageUserPct:       unitPercent,
+	containerCpuHostUsageSystemPct: unitPercent,
+	containerCpuHostUsageTotalPct:  unitPercent,
+	containerCpuHostUsageUserPct:   unitPercent,
+	containerCpuUsagePerCpuPct:     unitPercent,
+
+	containerMemoryWorkingSet:                 unitBytes,
+	containerMemoryUsage:                      unitBytes,
+	containerMemoryCache:                      unitBytes,
+	containerMemoryRSS:                        unitBytes,
+	containerMemoryHierarchicalDataPgfault:    unitCount,
+	containerMemoryHierarchicalDataPgmajfault: unitCount,
+	containerMemoryContainerDataPgfault:       unitCount,
+	containerMemoryContainerDataPgmajfault:    unitCount,
+	containerMemoryFailcnt:                    unitCount,
+
+	containerNetworkRxBytes:   unitBytes,
+	containerNetworkRxDropped: unitCount,
+	containerNetworkRxErrors:  unitCount,
+	containerNetworkRxPackets: unitCount,
+	containerNetworkTxBytes:   unitBytes,
+	containerNetworkTxDropped: unitCount,
+	containerNetworkTxErrors:  unitCount,
+	containerNetworkTxPackets: unitCount,
+
+	containerNetworkTcpStatEstablished: unitCount,
+	containerNetworkTcpStatSynSent:     unitCount,
+	containerNetworkTcpStatSynRecv:     unitCount,
+	containerNetworkTcpStatFinWait1:    unitCount,
+	containerNetworkTcpStatFinWait2:    unitCount,
+	containerNetworkTcpStatTimeWait:    unitCount,
+	containerNetworkTcpStatClose:       unitCount,
+	containerNetworkTcpStatCloseWait:   unitCount,
+	containerNetworkTcpStatLastAck:     unitCount,
+	containerNetworkTcpStatListen:      unitCount,
+	containerNetworkTcpStatClosing:     unitCount,
+
+	containerNetworkTcp6StatEstablished: unitCount,
+	containerNetworkTcp6StatSynSent:     unitCount,
+	containerNetworkTcp6StatSynRecv:     unitCount,
+	containerNetworkTcp6StatFinWait1:    unitCount,
+	containerNetworkTcp6StatFinWait2:    unitCount,
+	containerNetworkTcp6StatTimeWait:    unitCount,
+	containerNetworkTcp6StatClose:       unitCount,
+	containerNetworkTcp6StatCloseWait:   unitCount,
+	containerNetworkTcp6StatLastAck:     unitCount,
+	containerNetworkTcp6StatListen:      unitCount,
+	containerNetworkTcp6StatClosing:     unitCount,
+
+	containerTaskStatsNrIoWait:          unitCount,
+	containerTaskStatsNrRunning:         unitCount,
+	containerTaskStatsNrSleeping:        unitCount,
+	containerTaskStatsNrStopped:         unitCount,
+	containerTaskStatsNrUninterruptible: unitCount,
+
+	// disk io metrics are suffixed with the operation, for example cadvisor.diskio.ioservicebytes.read
+	containerDiskIoIoMerged:       unitCount,
+	containerDiskIoIoQueued:       unitCount,
+	containerDiskIoIoServiceBytes: unitBytes,
+	containerDiskIoIoServiced:     unitCount,
+	containerDiskIoIoServiceTime:  unitNanoseconds,
+	containerDiskIoIoTime:         unitMilliseconds,
+	containerDiskIoIoWaitTime:     unitNanoseconds,
+	containerDiskIoSectors:        unitCount,
+
+	containerFilesystemIoInProgress:    unitCount,
+	containerFilesystemIoTime:          unitMilliseconds,
+	containerFilesystemLimit:           unitBytes,
+	containerFilesystemReadsCompleted:  unitCount,
+	containerFilesystemReadsMerged:     unitCount,
+	containerFilesystemReadTime:        unitMilliseconds,
+	containerFilesystemSectorsRead:     unitCount,
+	containerFilesystemSectorsWritten:  unitCount,
+	containerFilesystemUsage:           unitBytes,
+	containerFilesystemBaseUsage:       unitBytes,
+	containerFilesystemAvailable:       unitBytes,
+	containerFilesystemInodesFree:      unitCount,
+	containerFilesystemWeightedIoTime:  unitMilliseconds,
+	containerFilesystemWritesCompleted: unitCount,
+	containerFilesystemWritesMerged:    unitCount,
+	containerFilesystemWriteTime:       unitMilliseconds,
+
+	hostCpuCores:              unitCount,
+	hostCpuFrequency:          unitKilohertz,
+	hostMemoryCapacity:        unitBytes,
+	hostFilesystemCapacity:    unitBytes,
+	hostFilesystemInodesTotal: unitCount,
+}
+
+func metricUnit(metric string) (string, bool) {
+	if unit, ok := metricUnits[metric]; ok {
+		return unit, true
+	}
+	if strings.HasPrefix(metric, "cadvisor.diskio.") {
+		if i := strings.LastIndex(metric, "."); i > 0 {
+			unit, ok := metricUnits[metric[:i]]
+			return unit, ok
+		}
+	}
+	return "", false
+}
+
+// unitCache remembers the units described for each entity so the units property is sent only when
+// an entity reports a metric it has not been described with yet. Like the entity tag cache it holds at most
+// limit entities evicting the least recently reported ones, entries expire ttl after they were described
+type unitCache struct {
+	limit int
+	ttl   time.Duration
+
+	mutex   sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+}
+
+type unitCacheEntry struct {
+	entity      string
+	units       map[string]string
+	describedAt time.Time
+}
+
+func newUnitCache(limit int, ttl time.Duration) *unitCache {
+	return &unitCache{limit: limit, ttl: ttl, entries: map[string]*list.Element{}, lru: list.New()}
+}
+
+// described returns the entry of the entity, a new one if it is not cached or has expired at timestamp
+func (self *unitCache) described(entity string, timestamp time.Time) *unitCacheEntry {
+	if el, ok := self.entries[entity]; ok {
+		entry := el.Value.(*unitCacheEntry)
+		if self.ttl <= 0 || timestamp.Sub(entry.describedAt) < self.ttl {
+			self.lru.MoveToFront(el)
+			return entry
+		}
+		self.lru.Remove(el)
+		delete(self.entries, entity)
+	}
+	entry := &unitCacheEntry{entity: entity, units: map[string]string{}}
+	self.entries[entity] = self.lru.PushFront(entry)
+	for self.limit > 0 && self.lru.Len() > self.limit {
+		oldest := self.lru.Back()
+		self.lru.Remove(oldest)
+		delete(self.entries, oldest.Value.(*unitCacheEntry).entity)
+	}
+	return entry
+}
+
+// UnitPropertyCommands returns the units property of the entity if the series report a metric the entity
+// has not been described with. The property holds the units of all metrics of the entity seen so far
+func (self *unitCache) UnitPropertyCommands(entity string, timestamp time.Time, seriesCommands ...[]*atsdNet.SeriesCommand) []*atsdNet.PropertyCommand {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	entry := self.described(entity, timestamp)
+	described := entry.units
+	changed := false
+	for _, commands := range seriesCommands {
+		for _, command := range commands {
+			for metric := range command.Metrics() {
+				if _, ok := described[metric]; ok {
+					continue
+				}
+				if unit, ok := metricUnit(metric); ok {
+					described[metric] = unit
+					changed = true
+				}
+			}
+		}
+	}
+	if !changed {
+		return []*atsdNet.PropertyCommand{}
+	}
+	entry.describedAt = timestamp
+	tags := make(map[string]string, len(described))
+	for metric, unit := range described {
+		tags[metric] = unit
+	}
+	propertyCommand := atsdNet.NewPropertyCommand(unitsPropertyType, entity, "", "").
+		SetAllTags(tags).
+		SetTimestamp(atsdNet.Millis(timestamp.UnixNano() / time.Millisecond.Nanoseconds()))
+	return []*atsdNet.PropertyCommand{propertyCommand}
+}
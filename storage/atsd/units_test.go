@@ -0,0 +1,104 @@
+// Copyright 2016 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package atsd
+
+import (
+	"testing"
+	"time"
+
+	atsdNet "github.com/axibase/atsd-api-go/net"
+)
+
+func TestUnitPropertyIsSentOnce(t *testing.T) {
+	cache := newUnitCache(unitCacheSize, unitCacheTTL)
+	entity := "hostname/test-entity"
+	timestamp := time.Unix(123456, 0)
+	memory := []*atsdNet.SeriesCommand{
+		atsdNet.NewSeriesCommand(entity, containerMemoryUsage, atsdNet.Uint64(123)).SetMetricValue(containerMemoryFailcnt, atsdNet.Uint64(1)),
+	}
+	diskIo := []*atsdNet.SeriesCommand{
+		atsdNet.NewSeriesCommand(entity, containerDiskIoIoServiceTime+".read", atsdNet.Uint64(1)),
+		atsdNet.NewSeriesCommand(entity, "unknown.metric", atsdNet.Uint64(1)),
+	}
+
+	properties := cache.UnitPropertyCommands(entity, timestamp, memory, diskIo)
+	if len(properties) != 1 {
+		t.Fatalf("%v properties have been sent, expected 1", len(properties))
+	}
+	property := properties[0]
+	if property.PropType() != unitsPropertyType || property.Entity() != entity {
+		t.Errorf("property type = %v, entity = %v", property.PropType(), property.Entity())
+	}
+	if ts := property.Timestamp(); ts == nil || *ts != atsdNet.Millis(123456000) {
+		t.Errorf("property timestamp = %v, expected 123456000", ts)
+	}
+	expected := map[string]string{
+		containerMemoryUsage:                   unitBytes,
+		containerMemoryFailcnt:                 unitCount,
+		containerDiskIoIoServiceTime + ".read": unitNanoseconds,
+	}
+	tags := property.Tags()
+	if len(tags) != len(expected) {
+		t.Errorf("property tags = %v, expected %v", tags, expected)
+	}
+	for metric, unit := range expected {
+		if tags[metric] != unit {
+			t.Errorf("unit of %v = %v, expected %v", metric, tags[metric], unit)
+		}
+	}
+
+	// subsequent intervals reporting the same metrics do not send the units again
+	for i := 0; i < 3; i++ {
+		if properties := cache.UnitPropertyCommands(entity, timestamp.Add(time.Duration(i+1)*time.Minute), memory, diskIo); len(properties) != 0 {
+			t.Errorf("interval %v: units have been sent again: %v", i+1, properties)
+		}
+	}
+
+	// a new metric sends the units of all metrics of the entity
+	network := []*atsdNet.SeriesCommand{atsdNet.NewSeriesCommand(entity, containerNetworkRxBytes, atsdNet.Uint64(1))}
+	properties = cache.UnitPropertyCommands(entity, timestamp, memory, network)
+	if len(properties) != 1 || len(properties[0].Tags()) != len(expected)+1 || properties[0].Tags()[containerNetworkRxBytes] != unitBytes {
+		t.Errorf("properties after a new metric = %v", properties)
+	}
+
+	// other entities are described on their own
+	if properties := cache.UnitPropertyCommands("hostname/other-entity", timestamp, memory); len(properties) != 1 {
+		t.Errorf("%v properties have been sent for another entity, expected 1", len(properties))
+	}
+}
+
+func TestUnitCacheIsBounded(t *testing.T) {
+	cache := newUnitCache(2, time.Hour)
+	timestamp := time.Unix(123456, 0)
+	series := func(entity string) []*atsdNet.SeriesCommand {
+		return []*atsdNet.SeriesCommand{atsdNet.NewSeriesCommand(entity, containerMemoryUsage, atsdNet.Uint64(1))}
+	}
+	for _, entity := range []string{"first", "second", "third"} {
+		cache.UnitPropertyCommands(entity, timestamp, series(entity))
+	}
+	if len(cache.entries) != 2 || cache.lru.Len() != 2 {
+		t.Errorf("cached %v entities, expected the limit of 2", len(cache.entries))
+	}
+	// the least recently reported entity has been forgotten and is described again
+	if properties := cache.UnitPropertyCommands("first", timestamp, series("first")); len(properties) != 1 {
+		t.Errorf("%v properties for an evicted entity, expected 1", len(properties))
+	}
+	if properties := cache.UnitPropertyCommands("third", timestamp.Add(30*time.Minute), series("third")); len(properties) != 0 {
+		t.Errorf("%v properties within the ttl, expected none", len(properties))
+	}
+	if properties := cache.UnitPropertyCommands("third", timestamp.Add(time.Hour), series("third")); len(properties) != 1 {
+		t.Errorf("%v properties after the ttl, expected the units to be described again", len(properties))
+	}
+}
@@ -30,6 +30,25 @@ type StorageDriver interface {
 	Close() error
 }
 
+// EventStorageDriver is implemented by storage drivers which record container events as well
+type EventStorageDriver interface {
+	StorageDriver
+
+	// RecordsEvents reports whether the driver is configured to record the events, no events are watched otherwise.
+	RecordsEvents() bool
+	// AddEvent records an event of the container. ref and spec are empty if the container
+	// is already gone.
+	AddEvent(ref info.ContainerReference, spec info.ContainerSpec, event *info.Event) error
+}
+
+// MachineStorageDriver is implemented by storage drivers which record the machine info as well
+type MachineStorageDriver interface {
+	StorageDriver
+
+	// AddMachineInfo records the hardware of the machine cAdvisor runs on.
+	AddMachineInfo(machineInfo *info.MachineInfo) error
+}
+
 type StorageDriverFunc func() (StorageDriver, error)
 
 var registeredPlugins = map[string](StorageDriverFunc){}
@@ -21,6 +21,9 @@ import (
 	"time"
 
 	"github.com/google/cadvisor/cache/memory"
+	"github.com/google/cadvisor/events"
+	info "github.com/google/cadvisor/info/v1"
+	"github.com/google/cadvisor/manager"
 	"github.com/google/cadvisor/storage"
 	_ "github.com/google/cadvisor/storage/atsd"
 	_ "github.com/google/cadvisor/storage/bigquery"
@@ -40,14 +43,62 @@ var (
 )
 
 // NewMemoryStorage creates a memory storage with an optional backend storage option.
-func NewMemoryStorage() (*memory.InMemoryCache, error) {
+// The backend storage is returned as well, nil if there is none.
+func NewMemoryStorage() (*memory.InMemoryCache, storage.StorageDriver, error) {
 	backendStorage, err := storage.New(*storageDriver)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	if *storageDriver != "" {
 		glog.Infof("Using backend storage type %q", *storageDriver)
 	}
 	glog.Infof("Caching stats in memory for %v", *storageDuration)
-	return memory.New(*storageDuration, backendStorage), nil
+	return memory.New(*storageDuration, backendStorage), backendStorage, nil
+}
+
+// ForwardMachineInfo hands the machine info to the backend storage if it records it.
+func ForwardMachineInfo(backendStorage storage.StorageDriver, containerManager manager.Manager) error {
+	machineStorage, ok := backendStorage.(storage.MachineStorageDriver)
+	if !ok {
+		return nil
+	}
+	machineInfo, err := containerManager.GetMachineInfo()
+	if err != nil {
+		return err
+	}
+	return machineStorage.AddMachineInfo(machineInfo)
+}
+
+// ForwardStorageEvents feeds the container events to the backend storage if it is configured to record them.
+func ForwardStorageEvents(backendStorage storage.StorageDriver, containerManager manager.Manager) error {
+	eventStorage, ok := backendStorage.(storage.EventStorageDriver)
+	if !ok || !eventStorage.RecordsEvents() {
+		return nil
+	}
+	request := events.NewRequest()
+	request.ContainerName = "/"
+	request.IncludeSubcontainers = true
+	for _, eventType := range []info.EventType{info.EventContainerCreation, info.EventContainerDeletion, info.EventOom, info.EventOomKill} {
+		request.EventType[eventType] = true
+	}
+	eventChannel, err := containerManager.WatchForEvents(request)
+	if err != nil {
+		return err
+	}
+	go func() {
+		for event := range eventChannel.GetChannel() {
+			var ref info.ContainerReference
+			var spec info.ContainerSpec
+			if event.EventType != info.EventContainerDeletion {
+				if containerInfo, err := containerManager.GetContainerInfo(event.ContainerName, &info.ContainerInfoRequest{NumStats: 1}); err == nil {
+					ref = containerInfo.ContainerReference
+					spec = containerInfo.Spec
+				}
+			}
+			if err := eventStorage.AddEvent(ref, spec, event); err != nil {
+				glog.Warningf("Failed to store event of container %q: %v", event.ContainerName, err)
+			}
+		}
+	}()
+	return nil
 }